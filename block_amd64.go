@@ -0,0 +1,48 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build amd64
+// +build amd64
+
+package blobloom
+
+import "golang.org/x/sys/cpu"
+
+// The assembly in block_amd64.s implements orBlock/hasAllBits with vector
+// instructions over a whole 64-byte block (one ZMM register with AVX-512,
+// two 32-byte YMM halves with AVX2), following the
+// "split block" approach of Impala/Kudu's block_bloom_filter_avx2.cc: the
+// mask is built in plain Go (see Add2/Has2 in bloomfilter.go), and only
+// the merge into, or test against, f.b[i] is vectorized.
+
+//go:noescape
+func orBlockAVX2(b, mask *block)
+
+//go:noescape
+func orBlockAVX512(b, mask *block)
+
+//go:noescape
+func hasAllAVX2(b, mask *block) bool
+
+//go:noescape
+func hasAllAVX512(b, mask *block) bool
+
+func init() {
+	switch {
+	case cpu.X86.HasAVX512F && cpu.X86.HasAVX512BW:
+		orBlock = orBlockAVX512
+		hasAllBits = hasAllAVX512
+	case cpu.X86.HasAVX2:
+		orBlock = orBlockAVX2
+		hasAllBits = hasAllAVX2
+	}
+}
@@ -55,8 +55,9 @@ const MaxBits = BlockBits << 32 // 256GiB.
 
 // A Filter is a blocked Bloom filter.
 type Filter struct {
-	b []block // Shards.
-	k int     // Number of hash functions required.
+	b    []block // Shards.
+	k    int     // Number of hash functions required.
+	fast bool    // Use the two-probes-per-word scheme of NewFast.
 }
 
 // New constructs a Bloom filter with given numbers of bits and hash functions.
@@ -68,20 +69,7 @@ type Filter struct {
 // The client passes the first two hashes for every key to Add and Has,
 // which synthesize all following hashes from the two values passed in.
 func New(nbits uint64, nhashes int) *Filter {
-	if nbits < 1 {
-		nbits = BlockBits
-	}
-	if nhashes < 2 {
-		nhashes = 2
-	}
-	if nbits > MaxBits {
-		panic("nbits exceeds MaxBits")
-	}
-
-	// Round nbits up to a multiple of BlockBits.
-	if nbits%BlockBits != 0 {
-		nbits += BlockBits - nbits%BlockBits
-	}
+	nbits, nhashes = normalizeShape(nbits, nhashes)
 
 	return &Filter{
 		b: make([]block, nbits/BlockBits),
@@ -89,6 +77,27 @@ func New(nbits uint64, nhashes int) *Filter {
 	}
 }
 
+// NewFast constructs a Bloom filter like New, but configured to use
+// RocksDB's dynamic_bloom trick for a faster Add/Has at the cost of a
+// somewhat higher false positive rate.
+//
+// Within the selected block, a Filter returned by New sets or tests one
+// bit per hash function. A Filter returned by NewFast instead treats the
+// block as eight 64-bit words and packs two probe bits into every word it
+// touches, so a lookup or insertion needs only about half as many memory
+// accesses. This trades a false positive rate roughly 1.1x worse, at the
+// same number of bits and hash functions, for a roughly 1.5-2x speedup on
+// write-heavy workloads (measurements due to Peter Dillinger).
+func NewFast(nbits uint64, nhashes int) *Filter {
+	nbits, nhashes = normalizeShape(nbits, nhashes)
+
+	return &Filter{
+		b:    make([]block, nbits/BlockBits),
+		k:    nhashes,
+		fast: true,
+	}
+}
+
 // Add insert a key with hash value h into f.
 //
 // The upper and lower half of h are treated as two independent hashes.
@@ -106,10 +115,17 @@ func (f *Filter) Add2(h1, h2 uint32) {
 	i := reducerange(h1, uint32(len(f.b)))
 	b := &f.b[i]
 
+	if f.fast {
+		addFast(b, h1, h2, f.k)
+		return
+	}
+
+	var mask block
 	for i := 0; i+1 < f.k; i++ {
 		h1, h2 = doublehash(h1, h2, i)
-		b.setbit(h1)
+		mask.setbit(h1)
 	}
+	orBlock(b, &mask)
 }
 
 // AddAtomic atomically inserts a key with hash value h into f.
@@ -129,6 +145,11 @@ func (f *Filter) AddAtomic2(h1, h2 uint32) {
 	i := reducerange(h1, uint32(len(f.b)))
 	b := &f.b[i]
 
+	if f.fast {
+		addFastAtomic(b, h1, h2, f.k)
+		return
+	}
+
 	for i := 0; i+1 < f.k; i++ {
 		h1, h2 = doublehash(h1, h2, i)
 		b.setbitAtomic(h1)
@@ -173,13 +194,38 @@ func (f *Filter) Has2(h1, h2 uint32) bool {
 	i := reducerange(h1, uint32(len(f.b)))
 	b := &f.b[i]
 
+	if f.fast {
+		return hasFast(b, h1, h2, f.k)
+	}
+
+	var mask block
 	for i := 0; i+1 < f.k; i++ {
 		h1, h2 = doublehash(h1, h2, i)
-		if !b.getbit(h1) {
-			return false
-		}
+		mask.setbit(h1)
 	}
-	return true
+	return hasAllBits(b, &mask)
+}
+
+// normalizeShape validates and rounds nbits and nhashes as New does,
+// shared with the other constructors in this package that build on the
+// same blocked layout.
+func normalizeShape(nbits uint64, nhashes int) (uint64, int) {
+	if nbits < 1 {
+		nbits = BlockBits
+	}
+	if nhashes < 2 {
+		nhashes = 2
+	}
+	if nbits > MaxBits {
+		panic("nbits exceeds MaxBits")
+	}
+
+	// Round nbits up to a multiple of BlockBits.
+	if nbits%BlockBits != 0 {
+		nbits += BlockBits - nbits%BlockBits
+	}
+
+	return nbits, nhashes
 }
 
 // doublehash generates the hash values n1, n2 to use in iteration i of
@@ -207,14 +253,38 @@ func (f *Filter) NumBits() uint64 {
 // hash functions. Both Filters must be using the same hash function(s),
 // but Union cannot check this.
 func (f *Filter) Union(g *Filter) {
+	checkSameShape(f, g)
+	for i := range f.b {
+		f.b[i].union(&g.b[i])
+	}
+}
+
+// Intersect sets f to the intersection of f and g.
+//
+// Intersect panics when f and g do not have the same number of bits and
+// hash functions, for the same reason Union does.
+func (f *Filter) Intersect(g *Filter) {
+	checkSameShape(f, g)
+	for i := range f.b {
+		f.b[i].intersect(&g.b[i])
+	}
+}
+
+// checkSameShape panics if f and g do not have the same number of bits
+// and hash functions, or were not constructed with the same bit layout
+// (New vs NewFast); mixing them would silently corrupt the result, since
+// the block bits mean different things to the two layouts. This is the
+// precondition shared by Union, Intersect, Jaccard and
+// IntersectionCardinality.
+func checkSameShape(f, g *Filter) {
 	if len(f.b) != len(g.b) {
 		panic("Bloom filters do not have the same number of bits")
 	}
 	if f.k != g.k {
 		panic("Bloom filters do not have the same number of hash functions")
 	}
-	for i := range f.b {
-		f.b[i].union(&g.b[i])
+	if f.fast != g.fast {
+		panic("Bloom filters do not have the same bit layout (New vs NewFast)")
 	}
 }
 
@@ -226,13 +296,6 @@ const (
 // A block is a fixed-size Bloom filter, used as a shard of a Filter.
 type block [blockSize]uint32
 
-// getbit reports whether bit (i modulo BlockBits) is set.
-func (b *block) getbit(i uint32) bool {
-	const n = uint32(len(*b))
-	x := (*b)[(i/wordSize)%n] & (1 << (i % wordSize))
-	return x != 0
-}
-
 // setbit sets bit (i modulo BlockBits) of b.
 func (b *block) setbit(i uint32) {
 	const n = uint32(len(*b))
@@ -294,3 +357,22 @@ func (b *block) union(c *block) {
 	b[14] |= c[14]
 	b[15] |= c[15]
 }
+
+func (b *block) intersect(c *block) {
+	b[0] &= c[0]
+	b[1] &= c[1]
+	b[2] &= c[2]
+	b[3] &= c[3]
+	b[4] &= c[4]
+	b[5] &= c[5]
+	b[6] &= c[6]
+	b[7] &= c[7]
+	b[8] &= c[8]
+	b[9] &= c[9]
+	b[10] &= c[10]
+	b[11] &= c[11]
+	b[12] &= c[12]
+	b[13] &= c[13]
+	b[14] &= c[14]
+	b[15] &= c[15]
+}
@@ -0,0 +1,336 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+// A CountingFilter is a Bloom filter that replaces each bit position with
+// a small saturating counter, so that, unlike a Filter, keys can be
+// removed again. It uses the same blocked layout and enhanced double
+// hashing construction as Filter, so it composes with the same hashing
+// choices.
+//
+// Counters saturate at their maximum value (15 for 4-bit counters, 255
+// for 8-bit ones) rather than overflowing, and Remove is a no-op on a
+// saturated counter, so that the "possibly in the set" invariant holds
+// even once a position has seen more insertions than its counter can
+// represent. This is the technique used by, e.g., Servo's counting Bloom
+// filter. Use SaturatedBits to detect when a filter starts entering this
+// approximate-deletion regime.
+type CountingFilter struct {
+	b4 []countingBlock4
+	b8 []countingBlock8
+	k  int
+}
+
+// NewCounting constructs a counting Bloom filter with the given numbers
+// of bits and hash functions, as New does, using counters of the given
+// width, which must be 4 or 8 bits.
+func NewCounting(nbits uint64, nhashes int, width int) *CountingFilter {
+	if width != 4 && width != 8 {
+		panic("blobloom: counter width must be 4 or 8")
+	}
+	nbits, nhashes = normalizeShape(nbits, nhashes)
+
+	f := &CountingFilter{k: nhashes}
+	n := nbits / BlockBits
+	if width == 4 {
+		f.b4 = make([]countingBlock4, n)
+	} else {
+		f.b8 = make([]countingBlock8, n)
+	}
+	return f
+}
+
+// numBlocks returns the number of blocks in f, regardless of counter
+// width.
+func (f *CountingFilter) numBlocks() int {
+	if f.b4 != nil {
+		return len(f.b4)
+	}
+	return len(f.b8)
+}
+
+// Add increments the counters for the k positions of a key with hash
+// value h in f, saturating rather than overflowing.
+func (f *CountingFilter) Add(h uint64) {
+	h1, h2 := uint32(h>>32), uint32(h)
+	i := reducerange(h1, uint32(f.numBlocks()))
+
+	if f.b4 != nil {
+		b := &f.b4[i]
+		for i := 0; i+1 < f.k; i++ {
+			h1, h2 = doublehash(h1, h2, i)
+			b.inc(h1)
+		}
+		return
+	}
+	b := &f.b8[i]
+	for i := 0; i+1 < f.k; i++ {
+		h1, h2 = doublehash(h1, h2, i)
+		b.inc(h1)
+	}
+}
+
+// Remove decrements the counters for the k positions of a key with hash
+// value h in f.
+//
+// Remove should only be called for a key that was previously added to f:
+// removing a key that was never added, or removing it more times than it
+// was added, corrupts the filter for unrelated keys that happen to share
+// one of its positions. Once a position's counter saturates, Remove can
+// no longer clear it; see SaturatedBits.
+func (f *CountingFilter) Remove(h uint64) {
+	h1, h2 := uint32(h>>32), uint32(h)
+	i := reducerange(h1, uint32(f.numBlocks()))
+
+	if f.b4 != nil {
+		b := &f.b4[i]
+		for i := 0; i+1 < f.k; i++ {
+			h1, h2 = doublehash(h1, h2, i)
+			b.dec(h1)
+		}
+		return
+	}
+	b := &f.b8[i]
+	for i := 0; i+1 < f.k; i++ {
+		h1, h2 = doublehash(h1, h2, i)
+		b.dec(h1)
+	}
+}
+
+// Has reports whether a key with hash value h has been added to f. It
+// may return a false positive.
+func (f *CountingFilter) Has(h uint64) bool {
+	h1, h2 := uint32(h>>32), uint32(h)
+	i := reducerange(h1, uint32(f.numBlocks()))
+
+	if f.b4 != nil {
+		b := &f.b4[i]
+		for i := 0; i+1 < f.k; i++ {
+			h1, h2 = doublehash(h1, h2, i)
+			if b.get(h1) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	b := &f.b8[i]
+	for i := 0; i+1 < f.k; i++ {
+		h1, h2 = doublehash(h1, h2, i)
+		if b.get(h1) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count estimates the number of times a key with hash value h has been
+// added to f, as the minimum of its k counters. Like Has, it may
+// overestimate but never underestimates the true count, except once a
+// counter has saturated.
+func (f *CountingFilter) Count(h uint64) uint8 {
+	h1, h2 := uint32(h>>32), uint32(h)
+	i := reducerange(h1, uint32(f.numBlocks()))
+
+	min := uint8(0xFF)
+	if f.b4 != nil {
+		min = 0xF
+		b := &f.b4[i]
+		for i := 0; i+1 < f.k; i++ {
+			h1, h2 = doublehash(h1, h2, i)
+			if v := b.get(h1); v < min {
+				min = v
+			}
+		}
+		return min
+	}
+	b := &f.b8[i]
+	for i := 0; i+1 < f.k; i++ {
+		h1, h2 = doublehash(h1, h2, i)
+		if v := b.get(h1); v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// SaturatedBits returns the number of counters in f that have reached
+// their maximum value and so can no longer be decremented by Remove.
+func (f *CountingFilter) SaturatedBits() uint64 {
+	var n uint64
+	if f.b4 != nil {
+		for i := range f.b4 {
+			n += uint64(f.b4[i].countSaturated())
+		}
+		return n
+	}
+	for i := range f.b8 {
+		n += uint64(f.b8[i].countSaturated())
+	}
+	return n
+}
+
+// checkSameShape panics if f and g do not have the same number of bits,
+// hash functions and counter width.
+func (f *CountingFilter) checkSameShape(g *CountingFilter) {
+	if f.numBlocks() != g.numBlocks() {
+		panic("blobloom: counting filters do not have the same number of bits")
+	}
+	if f.k != g.k {
+		panic("blobloom: counting filters do not have the same number of hash functions")
+	}
+	if (f.b4 != nil) != (g.b4 != nil) {
+		panic("blobloom: counting filters do not have the same counter width")
+	}
+}
+
+// Union sets f to the per-position maximum of the counters of f and g, so
+// that f reports a key as present, or a count for it, if either original
+// filter did.
+//
+// Union panics when f and g do not have the same number of bits, hash
+// functions and counter width.
+func (f *CountingFilter) Union(g *CountingFilter) {
+	f.checkSameShape(g)
+
+	if f.b4 != nil {
+		for i := range f.b4 {
+			f.b4[i].merge(&g.b4[i], max8)
+		}
+		return
+	}
+	for i := range f.b8 {
+		f.b8[i].merge(&g.b8[i], max8)
+	}
+}
+
+// Intersect sets f to the per-position minimum of the counters of f and
+// g, so that f's count for a key never exceeds what either original
+// filter would have reported.
+//
+// Intersect panics when f and g do not have the same number of bits, hash
+// functions and counter width.
+func (f *CountingFilter) Intersect(g *CountingFilter) {
+	f.checkSameShape(g)
+
+	if f.b4 != nil {
+		for i := range f.b4 {
+			f.b4[i].merge(&g.b4[i], min8)
+		}
+		return
+	}
+	for i := range f.b8 {
+		f.b8[i].merge(&g.b8[i], min8)
+	}
+}
+
+func max8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min8(a, b uint8) uint8 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// countingBlock4 is a shard of a CountingFilter using 4-bit counters:
+// BlockBits/2 bytes, each packing two nibble counters.
+type countingBlock4 [BlockBits / 2]uint8
+
+// get returns the counter at position (i modulo BlockBits).
+func (b *countingBlock4) get(i uint32) uint8 {
+	i %= BlockBits
+	shift := (i % 2) * 4
+	return (b[i/2] >> shift) & 0xF
+}
+
+// inc increments the counter at position (i modulo BlockBits), saturating
+// at 0xF.
+func (b *countingBlock4) inc(i uint32) {
+	i %= BlockBits
+	idx, shift := i/2, (i%2)*4
+	if (b[idx]>>shift)&0xF < 0xF {
+		b[idx] += 1 << shift
+	}
+}
+
+// dec decrements the counter at position (i modulo BlockBits). It is a
+// no-op at 0 and at the saturated value 0xF.
+func (b *countingBlock4) dec(i uint32) {
+	i %= BlockBits
+	idx, shift := i/2, (i%2)*4
+	if v := (b[idx] >> shift) & 0xF; v > 0 && v < 0xF {
+		b[idx] -= 1 << shift
+	}
+}
+
+func (b *countingBlock4) countSaturated() (n int) {
+	for _, c := range b {
+		if c&0xF == 0xF {
+			n++
+		}
+		if c>>4 == 0xF {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *countingBlock4) merge(c *countingBlock4, op func(a, b uint8) uint8) {
+	for i := range b {
+		lo := op(b[i]&0xF, c[i]&0xF)
+		hi := op(b[i]>>4, c[i]>>4)
+		b[i] = lo | hi<<4
+	}
+}
+
+// countingBlock8 is a shard of a CountingFilter using 8-bit counters: one
+// byte per position of an ordinary block.
+type countingBlock8 [BlockBits]uint8
+
+func (b *countingBlock8) get(i uint32) uint8 {
+	return b[i%BlockBits]
+}
+
+func (b *countingBlock8) inc(i uint32) {
+	i %= BlockBits
+	if b[i] < 0xFF {
+		b[i]++
+	}
+}
+
+func (b *countingBlock8) dec(i uint32) {
+	i %= BlockBits
+	if b[i] > 0 && b[i] < 0xFF {
+		b[i]--
+	}
+}
+
+func (b *countingBlock8) countSaturated() (n int) {
+	for _, c := range b {
+		if c == 0xFF {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *countingBlock8) merge(c *countingBlock8, op func(a, b uint8) uint8) {
+	for i := range b {
+		b[i] = op(b[i], c[i])
+	}
+}
@@ -0,0 +1,97 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import "testing"
+
+func TestCountingFilterAddHasRemove(t *testing.T) {
+	for _, width := range []int{4, 8} {
+		f := NewCounting(1<<16, 7, width)
+
+		const n = 1000
+		for i := uint64(0); i < n; i++ {
+			f.Add(i * 0x9e3779b97f4a7c15)
+		}
+		for i := uint64(0); i < n; i++ {
+			h := i * 0x9e3779b97f4a7c15
+			if !f.Has(h) {
+				t.Fatalf("width %d: Has(%d) = false after Add, want true", width, i)
+			}
+		}
+
+		for i := uint64(0); i < n; i++ {
+			f.Remove(i * 0x9e3779b97f4a7c15)
+		}
+		var falsePositives int
+		for i := uint64(0); i < n; i++ {
+			h := i * 0x9e3779b97f4a7c15
+			if f.Has(h) {
+				falsePositives++
+			}
+		}
+		// Removing every added key should clear all of them: the filter
+		// starts empty, so a counter can only be shared among this test's
+		// own keys,
+		// and since every key that incremented it is also removed, the
+		// counter nets back to zero (short of saturation, which 1000
+		// additions spread over many blocks won't reach).
+		if falsePositives != 0 {
+			t.Errorf("width %d: %d/%d keys still present after Remove", width, falsePositives, n)
+		}
+	}
+}
+
+func TestCountingFilterSaturates4Bit(t *testing.T) {
+	f := NewCounting(BlockBits, 2, 4)
+	for i := 0; i < 20; i++ {
+		f.Add(0)
+	}
+	if got := f.Count(0); got != 0xF {
+		t.Errorf("Count = %d after 20 Adds to a 4-bit counter, want saturated value 15", got)
+	}
+	if f.SaturatedBits() == 0 {
+		t.Error("SaturatedBits = 0, want at least one saturated counter")
+	}
+}
+
+func TestCountingFilterUnionIntersect(t *testing.T) {
+	f := NewCounting(1<<16, 4, 8)
+	g := NewCounting(1<<16, 4, 8)
+
+	for i := uint64(0); i < 500; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(250); i < 750; i++ {
+		g.Add(i * 0x9e3779b97f4a7c15)
+	}
+
+	union := NewCounting(1<<16, 4, 8)
+	for i := uint64(0); i < 500; i++ {
+		union.Add(i * 0x9e3779b97f4a7c15)
+	}
+	union.Union(g)
+	for i := uint64(0); i < 750; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !union.Has(h) {
+			t.Fatalf("Has(%d) = false after Union, want true", i)
+		}
+	}
+
+	f.Intersect(g)
+	for i := uint64(250); i < 500; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !f.Has(h) {
+			t.Fatalf("Has(%d) = false after Intersect of overlapping filters, want true", i)
+		}
+	}
+}
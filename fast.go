@@ -0,0 +1,93 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// words64 reinterprets b as eight 64-bit words, the view used by
+// NewFast's addFast/hasFast.
+func (b *block) words64() *[8]uint64 {
+	return (*[8]uint64)(unsafe.Pointer(b))
+}
+
+// addFast sets the probe bits for a key with hashes h1, h2 in b, using
+// the two-probes-per-word scheme described at NewFast: it derives
+// ceil((k-1)/2) fresh 64-bit values from h1, h2 by multiplicative
+// remixing, and for each one sets two bits in a single word of b.
+func addFast(b *block, h1, h2 uint32, k int) {
+	words := b.words64()
+	h := uint64(h1)<<32 | uint64(h2)
+
+	for i := 0; i+1 < k; i += 2 {
+		h *= 0x9e3779b97f4a7c15
+		h ^= h >> 32
+		hi, lo := uint32(h>>32), uint32(h)
+
+		w := reducerange(hi, 8)
+		words[w] |= uint64(1)<<(lo&63) | uint64(1)<<((lo>>6)&63)
+	}
+}
+
+// addFastAtomic is the atomic equivalent of addFast, used by
+// AddAtomic2 when f.fast is set.
+func addFastAtomic(b *block, h1, h2 uint32, k int) {
+	words := b.words64()
+	h := uint64(h1)<<32 | uint64(h2)
+
+	for i := 0; i+1 < k; i += 2 {
+		h *= 0x9e3779b97f4a7c15
+		h ^= h >> 32
+		hi, lo := uint32(h>>32), uint32(h)
+
+		w := reducerange(hi, 8)
+		mask := uint64(1)<<(lo&63) | uint64(1)<<((lo>>6)&63)
+		orUint64Atomic(&words[w], mask)
+	}
+}
+
+// orUint64Atomic atomically ORs mask into *p, the 64-bit equivalent of
+// block.setbitAtomic.
+func orUint64Atomic(p *uint64, mask uint64) {
+	for {
+		old := atomic.LoadUint64(p)
+		if old&mask == mask {
+			// Checking here instead of checking the return value from
+			// the CAS is between 25% and 50% faster on the benchmark.
+			return
+		}
+		atomic.CompareAndSwapUint64(p, old, old|mask)
+	}
+}
+
+// hasFast reports whether all the probe bits addFast would set for a key
+// with hashes h1, h2 are set in b.
+func hasFast(b *block, h1, h2 uint32, k int) bool {
+	words := b.words64()
+	h := uint64(h1)<<32 | uint64(h2)
+
+	for i := 0; i+1 < k; i += 2 {
+		h *= 0x9e3779b97f4a7c15
+		h ^= h >> 32
+		hi, lo := uint32(h>>32), uint32(h)
+
+		w := reducerange(hi, 8)
+		mask := uint64(1)<<(lo&63) | uint64(1)<<((lo>>6)&63)
+		if words[w]&mask != mask {
+			return false
+		}
+	}
+	return true
+}
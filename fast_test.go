@@ -0,0 +1,71 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import "testing"
+
+func TestNewFastAddHas(t *testing.T) {
+	f := NewFast(1<<16, 7)
+
+	const n = 2000
+	for i := uint64(0); i < n; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(0); i < n; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !f.Has(h) {
+			t.Fatalf("Has(%d) = false, want true (no false negatives allowed)", i)
+		}
+	}
+}
+
+func TestNewFastAddAtomicHas(t *testing.T) {
+	f := NewFast(1<<16, 5)
+
+	const n = 2000
+	for i := uint64(0); i < n; i++ {
+		f.AddAtomic(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(0); i < n; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !f.Has(h) {
+			t.Fatalf("Has(%d) = false after AddAtomic, want true", i)
+		}
+	}
+}
+
+func TestNewFastMarshalRoundTrip(t *testing.T) {
+	f := NewFast(1<<16, 7)
+	for i := uint64(0); i < 1000; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	g := new(Filter)
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !f.Equal(g) {
+		t.Fatal("round-tripped NewFast filter is not Equal to the original")
+	}
+	for i := uint64(0); i < 1000; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !g.Has(h) {
+			t.Fatalf("Has(%d) = false after round trip, want true", i)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// magic identifies the binary format written by MarshalBinary/WriteTo.
+const magic uint32 = 0xb10b10
+
+// formatVersion is incremented whenever the binary format changes in a
+// backwards-incompatible way.
+const formatVersion uint32 = 2
+
+// headerSize is the size, in bytes, of the encoded header: magic,
+// version, BlockBits, k, flags and a block count.
+const headerSize = 4 + 4 + 4 + 4 + 4 + 8
+
+// flagFast marks a filter constructed with NewFast in the flags word of
+// the header, so that ReadFrom can restore the bit layout Add2/Has2 need
+// to use for it.
+const flagFast uint32 = 1 << 0
+
+// MarshalBinary encodes f in a portable binary format: a header recording
+// a magic number, the format version, BlockBits, the number of hash
+// functions, a flags word (recording whether f was constructed with
+// NewFast) and the number of blocks, followed by the blocks themselves
+// as little-endian uint32s. Like LevelDB's Bloom filter encoding, the
+// parameters needed to reconstruct f travel with the data, so a filter
+// written by one build of this package, on one architecture, can be read
+// back correctly by another.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(headerSize + len(f.b)*blockSize*4)
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary or WriteTo
+// into f, replacing its previous contents.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	_, err := f.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes f to w in the format produced by MarshalBinary.
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, headerSize)
+	var flags uint32
+	if f.fast {
+		flags |= flagFast
+	}
+
+	binary.LittleEndian.PutUint32(header[0:], magic)
+	binary.LittleEndian.PutUint32(header[4:], formatVersion)
+	binary.LittleEndian.PutUint32(header[8:], BlockBits)
+	binary.LittleEndian.PutUint32(header[12:], uint32(f.k))
+	binary.LittleEndian.PutUint32(header[16:], flags)
+	binary.LittleEndian.PutUint64(header[20:], uint64(len(f.b)))
+
+	n, err := w.Write(header)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	buf := make([]byte, blockSize*4)
+	for i := range f.b {
+		for j, word := range f.b[i] {
+			binary.LittleEndian.PutUint32(buf[j*4:], word)
+		}
+		n, err = w.Write(buf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom replaces f's contents with a filter read from r in the format
+// produced by WriteTo/MarshalBinary.
+//
+// ReadFrom rejects data with an unrecognized magic number or format
+// version, data whose declared BlockBits does not match the BlockBits of
+// the package reading it, and data whose declared block count would
+// exceed MaxBits, returning a clear error rather than silently
+// misinterpreting the bytes or allocating based on unvalidated input.
+func (f *Filter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(r, header)
+	total := int64(n)
+	if err != nil {
+		return total, fmt.Errorf("blobloom: reading filter header: %w", err)
+	}
+
+	gotMagic := binary.LittleEndian.Uint32(header[0:])
+	if gotMagic != magic {
+		return total, fmt.Errorf("blobloom: not a blobloom filter (bad magic number %#x)", gotMagic)
+	}
+	version := binary.LittleEndian.Uint32(header[4:])
+	if version != formatVersion {
+		return total, fmt.Errorf("blobloom: unsupported format version %d", version)
+	}
+	blockBits := binary.LittleEndian.Uint32(header[8:])
+	if blockBits != BlockBits {
+		return total, fmt.Errorf("blobloom: filter has %d bits per block, this build uses %d", blockBits, BlockBits)
+	}
+	k := binary.LittleEndian.Uint32(header[12:])
+	flags := binary.LittleEndian.Uint32(header[16:])
+	numBlocks := binary.LittleEndian.Uint64(header[20:])
+	if numBlocks > MaxBits/BlockBits {
+		return total, fmt.Errorf("blobloom: declared block count %d exceeds MaxBits", numBlocks)
+	}
+
+	b := make([]block, numBlocks)
+	buf := make([]byte, blockSize*4)
+	for i := range b {
+		n, err = io.ReadFull(r, buf)
+		total += int64(n)
+		if err != nil {
+			return total, fmt.Errorf("blobloom: reading block %d: %w", i, err)
+		}
+		for j := range b[i] {
+			b[i][j] = binary.LittleEndian.Uint32(buf[j*4:])
+		}
+	}
+
+	f.b = b
+	f.k = int(k)
+	f.fast = flags&flagFast != 0
+	return total, nil
+}
+
+// Equal reports whether f and g have the same number of hash functions,
+// use the same bit layout (see NewFast), and contain exactly the same
+// bits. It is meant for use in tests.
+func (f *Filter) Equal(g *Filter) bool {
+	if f.k != g.k || f.fast != g.fast || len(f.b) != len(g.b) {
+		return false
+	}
+	for i := range f.b {
+		if f.b[i] != g.b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	f := New(1<<16, 7)
+	for i := uint64(0); i < 1000; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	g := new(Filter)
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !f.Equal(g) {
+		t.Fatal("round-tripped filter is not Equal to the original")
+	}
+	for i := uint64(0); i < 1000; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !g.Has(h) {
+			t.Fatalf("Has(%d) = false after round trip, want true", i)
+		}
+	}
+}
+
+func TestReadFromRejectsOversizedBlockCount(t *testing.T) {
+	f := New(BlockBits, 4)
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	// Overwrite the declared block count with a value that would require
+	// allocating far more memory than MaxBits allows.
+	binary.LittleEndian.PutUint64(data[20:], 1<<40)
+
+	g := new(Filter)
+	_, err = g.ReadFrom(bytes.NewReader(data))
+	if err == nil {
+		t.Fatal("ReadFrom accepted a block count that exceeds MaxBits, want an error")
+	}
+}
+
+func TestReadFromRejectsBadMagic(t *testing.T) {
+	f := New(BlockBits, 4)
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	binary.LittleEndian.PutUint32(data[0:], 0xdeadbeef)
+
+	g := new(Filter)
+	if _, err := g.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Fatal("ReadFrom accepted data with a bad magic number, want an error")
+	}
+}
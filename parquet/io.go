@@ -0,0 +1,65 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteTo writes f's bitset to w, using the little-endian byte layout the
+// Parquet specification mandates for the BLOCK algorithm.
+//
+// It writes only the bitset itself, not the surrounding Thrift-encoded
+// BloomFilterHeader that accompanies it in a Parquet file.
+func (f *SplitBlockFilter) WriteTo(w io.Writer) (int64, error) {
+	buf := make([]byte, blockBits/8)
+	var total int64
+	for i := range f.b {
+		for j, word := range f.b[i] {
+			binary.LittleEndian.PutUint32(buf[j*4:], word)
+		}
+		n, err := w.Write(buf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom replaces f's bitset with one read from r, in the format
+// written by WriteTo. The number of blocks is determined by the amount
+// of data read, which must be a whole number of blocks.
+func (f *SplitBlockFilter) ReadFrom(r io.Reader) (int64, error) {
+	data, err := io.ReadAll(r)
+	total := int64(len(data))
+	if err != nil {
+		return total, err
+	}
+	if len(data)%(blockBits/8) != 0 {
+		return total, fmt.Errorf("parquet: bitset length %d is not a multiple of the block size", len(data))
+	}
+
+	b := make([]block, len(data)/(blockBits/8))
+	for i := range b {
+		chunk := data[i*(blockBits/8):]
+		for j := range b[i] {
+			b[i][j] = binary.LittleEndian.Uint32(chunk[j*4:])
+		}
+	}
+
+	f.b = b
+	return total, nil
+}
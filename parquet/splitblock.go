@@ -0,0 +1,130 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package parquet implements the Split Block Bloom Filter described by
+// the Apache Parquet format (PARQUET-41):
+// https://github.com/apache/parquet-format/blob/master/BloomFilter.md.
+//
+// A SplitBlockFilter is, like blobloom.Filter, a blocked Bloom filter, but
+// with 256-bit blocks of eight 32-bit words and a fixed set of salting
+// constants for turning a hash into a block's bit mask, both mandated by
+// the Parquet spec so that filters are portable across implementations.
+// Because the block size and salts differ from blobloom.Filter's, and
+// because the Parquet format requires keys to be hashed with xxhash64,
+// this package keeps its own small block implementation rather than
+// generalizing the parent package's fixed 512-bit block.
+//
+// This package only handles the filter's bitset; the Thrift-encoded
+// BloomFilterHeader that precedes it in a Parquet file is the caller's
+// responsibility.
+package parquet
+
+import "math"
+
+// blockBits is the number of bits in a split block, as fixed by the
+// Parquet specification.
+const blockBits = 256
+
+const blockWords = blockBits / 32
+
+// salt holds the eight odd constants the Parquet specification uses to
+// spread a 32-bit hash across the words of a block.
+var salt = [blockWords]uint32{
+	0x47b6137b, 0x44974d91, 0x8824ad5b, 0xa2b7289d,
+	0x705495c7, 0x2df1424b, 0x9efc4947, 0x5c6bfb31,
+}
+
+// A block is a split block: an ordinary Bloom filter of blockBits bits,
+// stored as blockWords 32-bit words.
+type block [blockWords]uint32
+
+// mask returns the bit mask that Insert/Check set/test within a block for
+// the given 32-bit hash.
+func mask(h uint32) block {
+	var m block
+	for i, s := range salt {
+		m[i] = 1 << ((h * s) >> 27)
+	}
+	return m
+}
+
+// A SplitBlockFilter is a Parquet split block Bloom filter.
+//
+// Keys must be hashed with xxhash64, as mandated by the Parquet
+// specification; this package does not hash keys itself.
+type SplitBlockFilter struct {
+	b []block
+}
+
+// NewFromNDV constructs a SplitBlockFilter sized for ndv distinct values
+// at false positive probability fpp, using the sizing formula from the
+// Parquet specification.
+func NewFromNDV(ndv uint64, fpp float64) *SplitBlockFilter {
+	return &SplitBlockFilter{
+		b: make([]block, numBlocks(ndv, fpp)),
+	}
+}
+
+// numBlocks computes the number of blocks needed for ndv distinct values
+// at false positive probability fpp, rounding up to a power of two as the
+// Parquet specification requires.
+func numBlocks(ndv uint64, fpp float64) uint64 {
+	const (
+		minBlocks = 1
+		maxBlocks = 1 << 26 // 2 GiB of blocks, the spec's upper bound.
+	)
+
+	numBits := -8 * float64(ndv) / math.Log(1-math.Pow(fpp, 1.0/8))
+	n := uint64(numBits/blockBits) + 1
+
+	pow2 := uint64(1)
+	for pow2 < n {
+		pow2 <<= 1
+	}
+
+	switch {
+	case pow2 < minBlocks:
+		return minBlocks
+	case pow2 > maxBlocks:
+		return maxBlocks
+	}
+	return pow2
+}
+
+// Insert adds a key with xxhash64 hash value h to f.
+func (f *SplitBlockFilter) Insert(h uint64) {
+	b := &f.b[blockIndex(h, uint64(len(f.b)))]
+	m := mask(uint32(h))
+	for i := range b {
+		b[i] |= m[i]
+	}
+}
+
+// Check reports whether a key with xxhash64 hash value h may have been
+// added to f. It may return a false positive, but never a false
+// negative.
+func (f *SplitBlockFilter) Check(h uint64) bool {
+	b := &f.b[blockIndex(h, uint64(len(f.b)))]
+	m := mask(uint32(h))
+	for i := range b {
+		if b[i]&m[i] != m[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// blockIndex selects a block out of n for hash h, using the upper 32 bits
+// of h as the Parquet specification requires.
+func blockIndex(h, n uint64) uint64 {
+	return ((h >> 32) * n) >> 32
+}
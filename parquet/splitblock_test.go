@@ -0,0 +1,63 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInsertCheck(t *testing.T) {
+	f := NewFromNDV(10000, 0.01)
+
+	const n = 5000
+	for i := uint64(0); i < n; i++ {
+		f.Insert(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(0); i < n; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !f.Check(h) {
+			t.Fatalf("Check(%d) = false, want true (no false negatives allowed)", i)
+		}
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	f := NewFromNDV(1000, 0.01)
+	for i := uint64(0); i < 500; i++ {
+		f.Insert(i * 0x9e3779b97f4a7c15)
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	g := new(SplitBlockFilter)
+	if _, err := g.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	for i := uint64(0); i < 500; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !g.Check(h) {
+			t.Fatalf("Check(%d) = false after round trip, want true", i)
+		}
+	}
+}
+
+func TestNumBlocksPowerOfTwo(t *testing.T) {
+	n := numBlocks(1_000_000, 0.01)
+	if n&(n-1) != 0 {
+		t.Errorf("numBlocks returned %d, which is not a power of two", n)
+	}
+}
@@ -0,0 +1,304 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// Default parameters for NewScalable, following Almeida, Baquero,
+// Preguiça and Hutchison's scalable Bloom filter construction (2007).
+const (
+	defaultGrowth          = 2
+	defaultTighteningRatio = 0.9
+)
+
+// scalableMagic identifies the binary format written by
+// ScalableFilter.MarshalBinary/WriteTo.
+const scalableMagic uint32 = 0xb10bca1e
+
+// scalableFormatVersion is incremented whenever the ScalableFilter binary
+// format changes in a backwards-incompatible way.
+const scalableFormatVersion uint32 = 1
+
+// scalableHeaderSize is the size, in bytes, of the encoded header: magic,
+// version, growth, tighten, lastFPR and a stage count.
+const scalableHeaderSize = 4 + 4 + 8 + 8 + 8 + 4
+
+// maxScalableStages bounds the stage count ReadFrom trusts from a
+// header, so that a corrupt or hostile header cannot force it to
+// allocate an unreasonable number of stages before reading any of them.
+// It is far larger than any real ScalableFilter would ever need.
+const maxScalableStages = 1 << 20
+
+// A ScalableFilter is a Bloom filter that grows to accommodate a number
+// of keys that isn't known in advance, trading a little memory
+// efficiency for not having to size a Filter up front.
+//
+// It is a chain of ordinary Filters ("stages") of geometrically
+// increasing capacity. Add writes into the current stage; once the number
+// of Add and AddAtomic calls on that stage reaches its design capacity (a
+// key added more than once counts each time), a new,
+// larger stage is allocated with a tighter false positive rate (FPR), so
+// that the compound FPR of the whole chain stays bounded by the target
+// passed to NewScalable. Has reports a key as present if any stage does.
+type ScalableFilter struct {
+	stages []*Filter
+	design []float64 // Target cardinality of each stage, parallel to stages.
+
+	// count is the number of keys added to the current (last) stage,
+	// tracked incrementally so growIfFull can check it in O(1) rather
+	// than recomputing Cardinality (an O(stage size) scan) on every Add.
+	// It is manipulated with sync/atomic so that AddAtomic's contribution
+	// is accounted for even though it runs concurrently with itself.
+	count uint64
+
+	growth  float64
+	tighten float64
+	lastFPR float64
+}
+
+// NewScalable constructs a ScalableFilter whose first stage holds
+// approximately initialCapacity keys, growing later stages by a factor
+// of 2 with a per-stage FPR tightened by a ratio of 0.9, so that the
+// compound false positive rate of the whole filter is bounded by
+// targetFPR.
+func NewScalable(initialCapacity uint64, targetFPR float64) *ScalableFilter {
+	if initialCapacity < 1 {
+		initialCapacity = 1
+	}
+	if targetFPR <= 0 || targetFPR >= 1 {
+		panic("blobloom: targetFPR must be between 0 and 1")
+	}
+
+	f := &ScalableFilter{
+		growth:  defaultGrowth,
+		tighten: defaultTighteningRatio,
+	}
+	f.addStage(initialCapacity, targetFPR*(1-defaultTighteningRatio))
+	return f
+}
+
+// addStage appends a new stage sized for capacity keys at false positive
+// rate fpr.
+func (f *ScalableFilter) addStage(capacity uint64, fpr float64) {
+	nbits, k := sizeFilter(capacity, fpr)
+	f.stages = append(f.stages, New(nbits, k))
+	f.design = append(f.design, float64(capacity))
+	f.lastFPR = fpr
+	f.count = 0
+}
+
+// sizeFilter computes a number of bits and hash functions for a Filter
+// sized for n keys at false positive rate fpr, using the standard
+// formulas m = -n·ln(fpr)/ln(2)² and k = (m/n)·ln 2.
+func sizeFilter(n uint64, fpr float64) (nbits uint64, k int) {
+	m := -float64(n) * math.Log(fpr) / (math.Ln2 * math.Ln2)
+	k = int(math.Round(m / float64(n) * math.Ln2))
+	return uint64(m), k
+}
+
+// Add inserts a key with hash value h into f, growing f with a new,
+// larger stage first if the current stage has reached its design
+// capacity.
+func (f *ScalableFilter) Add(h uint64) {
+	f.growIfFull()
+	f.stages[len(f.stages)-1].Add(h)
+	atomic.AddUint64(&f.count, 1)
+}
+
+// growIfFull adds a new stage if the current one has reached its design
+// capacity.
+func (f *ScalableFilter) growIfFull() {
+	i := len(f.stages) - 1
+	if float64(atomic.LoadUint64(&f.count)) < f.design[i] {
+		return
+	}
+	f.addStage(uint64(f.design[i]*f.growth), f.lastFPR*f.tighten)
+}
+
+// AddAtomic atomically inserts a key with hash value h into the current
+// stage of f.
+//
+// As with Filter.AddAtomic, multiple goroutines may call AddAtomic
+// concurrently, one per stage, as long as no other method that could
+// grow or otherwise mutate f is called concurrently with them: unlike
+// Add, AddAtomic never allocates a new stage, so callers that need f to
+// grow under concurrent use must size initialCapacity generously enough,
+// or call Add from a single goroutine between bursts of AddAtomic calls.
+func (f *ScalableFilter) AddAtomic(h uint64) {
+	f.stages[len(f.stages)-1].AddAtomic(h)
+	atomic.AddUint64(&f.count, 1)
+}
+
+// Has reports whether a key with hash value h may have been added to f.
+// It may return a false positive, but never a false negative.
+func (f *ScalableFilter) Has(h uint64) bool {
+	for _, s := range f.stages {
+		if s.Has(h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cardinality estimates the number of distinct keys added to f, by
+// summing the estimates of its stages.
+func (f *ScalableFilter) Cardinality() float64 {
+	var n float64
+	for _, s := range f.stages {
+		n += s.Cardinality()
+	}
+	return n
+}
+
+// NumBits returns the total number of bits across all stages of f.
+func (f *ScalableFilter) NumBits() uint64 {
+	var n uint64
+	for _, s := range f.stages {
+		n += s.NumBits()
+	}
+	return n
+}
+
+// MarshalBinary encodes f in the format written by WriteTo: a header
+// recording a magic number, the format version, the growth factor,
+// tightening ratio and most recently used stage FPR, and a stage count,
+// followed by each stage's design cardinality, length and
+// Filter.MarshalBinary encoding.
+func (f *ScalableFilter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary or WriteTo
+// into f, replacing its previous contents.
+func (f *ScalableFilter) UnmarshalBinary(data []byte) error {
+	_, err := f.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes f to w in the format produced by MarshalBinary.
+func (f *ScalableFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, scalableHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], scalableMagic)
+	binary.LittleEndian.PutUint32(header[4:], scalableFormatVersion)
+	binary.LittleEndian.PutUint64(header[8:], math.Float64bits(f.growth))
+	binary.LittleEndian.PutUint64(header[16:], math.Float64bits(f.tighten))
+	binary.LittleEndian.PutUint64(header[24:], math.Float64bits(f.lastFPR))
+	binary.LittleEndian.PutUint32(header[32:], uint32(len(f.stages)))
+
+	n, err := w.Write(header)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	stageHeader := make([]byte, 8+4)
+	for i, s := range f.stages {
+		enc, err := s.MarshalBinary()
+		if err != nil {
+			return total, err
+		}
+
+		binary.LittleEndian.PutUint64(stageHeader[0:], math.Float64bits(f.design[i]))
+		binary.LittleEndian.PutUint32(stageHeader[8:], uint32(len(enc)))
+		n, err = w.Write(stageHeader)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+
+		n, err = w.Write(enc)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom replaces f's contents with a filter read from r in the format
+// produced by WriteTo/MarshalBinary.
+//
+// ReadFrom rejects data with an unrecognized magic number or format
+// version, and data whose declared stage count exceeds a sanity bound,
+// before trusting it to size any allocation; each stage's own bytes are
+// decoded by Filter.ReadFrom, which bounds its block count by MaxBits.
+func (f *ScalableFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, scalableHeaderSize)
+	n, err := io.ReadFull(r, header)
+	total := int64(n)
+	if err != nil {
+		return total, fmt.Errorf("blobloom: reading scalable filter header: %w", err)
+	}
+
+	gotMagic := binary.LittleEndian.Uint32(header[0:])
+	if gotMagic != scalableMagic {
+		return total, fmt.Errorf("blobloom: not a blobloom ScalableFilter (bad magic number %#x)", gotMagic)
+	}
+	version := binary.LittleEndian.Uint32(header[4:])
+	if version != scalableFormatVersion {
+		return total, fmt.Errorf("blobloom: unsupported ScalableFilter format version %d", version)
+	}
+
+	growth := math.Float64frombits(binary.LittleEndian.Uint64(header[8:]))
+	tighten := math.Float64frombits(binary.LittleEndian.Uint64(header[16:]))
+	lastFPR := math.Float64frombits(binary.LittleEndian.Uint64(header[24:]))
+	numStages := binary.LittleEndian.Uint32(header[32:])
+	if numStages > maxScalableStages {
+		return total, fmt.Errorf("blobloom: declared stage count %d exceeds limit", numStages)
+	}
+
+	stages := make([]*Filter, numStages)
+	design := make([]float64, numStages)
+	stageHeader := make([]byte, 8+4)
+	for i := range stages {
+		n, err = io.ReadFull(r, stageHeader)
+		total += int64(n)
+		if err != nil {
+			return total, fmt.Errorf("blobloom: reading stage %d header: %w", i, err)
+		}
+		design[i] = math.Float64frombits(binary.LittleEndian.Uint64(stageHeader[0:]))
+		stageLen := int64(binary.LittleEndian.Uint32(stageHeader[8:]))
+
+		s := new(Filter)
+		sn, err := s.ReadFrom(io.LimitReader(r, stageLen))
+		total += sn
+		if err != nil {
+			return total, fmt.Errorf("blobloom: decoding stage %d: %w", i, err)
+		}
+		stages[i] = s
+	}
+
+	f.stages = stages
+	f.design = design
+	f.growth = growth
+	f.tighten = tighten
+	f.lastFPR = lastFPR
+	if len(stages) > 0 {
+		// count isn't part of the wire format; reestablish it from the
+		// restored stage's own estimate so growIfFull's O(1) check stays
+		// accurate after a round trip.
+		f.count = uint64(stages[len(stages)-1].Cardinality())
+	}
+	return total, nil
+}
@@ -0,0 +1,79 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestScalableFilterGrows(t *testing.T) {
+	f := NewScalable(100, 0.01)
+
+	const n = 10000
+	for i := uint64(0); i < n; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+	if len(f.stages) < 2 {
+		t.Errorf("got %d stages after adding %d keys to a filter sized for 100, want more than one", len(f.stages), n)
+	}
+	for i := uint64(0); i < n; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !f.Has(h) {
+			t.Fatalf("Has(%d) = false, want true (no false negatives allowed)", i)
+		}
+	}
+}
+
+func TestScalableFilterMarshalRoundTrip(t *testing.T) {
+	f := NewScalable(100, 0.01)
+	for i := uint64(0); i < 5000; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	g := new(ScalableFilter)
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if len(g.stages) != len(f.stages) {
+		t.Fatalf("got %d stages after round trip, want %d", len(g.stages), len(f.stages))
+	}
+	for i := uint64(0); i < 5000; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !g.Has(h) {
+			t.Fatalf("Has(%d) = false after round trip, want true", i)
+		}
+	}
+}
+
+func TestScalableFilterReadFromRejectsOversizedStageCount(t *testing.T) {
+	f := NewScalable(100, 0.01)
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	// Overwrite the declared stage count with a value beyond maxScalableStages.
+	binary.LittleEndian.PutUint32(data[32:], 1<<30)
+
+	g := new(ScalableFilter)
+	if _, err := g.ReadFrom(bytes.NewReader(data)); err == nil {
+		t.Fatal("ReadFrom accepted a stage count beyond maxScalableStages, want an error")
+	}
+}
@@ -0,0 +1,69 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import "math"
+
+// IntersectionCardinality estimates the number of keys common to both f
+// and g, using the inclusion-exclusion principle on the cardinality
+// estimates of f, g and their union, following the approach of Swamidass
+// and Baldi (2007), adapted to blocked Bloom filters the same way
+// Cardinality is: a per-block maximum likelihood estimate, summed over
+// blocks.
+//
+// IntersectionCardinality panics when f and g do not have the same
+// number of bits and hash functions, the same requirement as Union and
+// Intersect. It does not modify f or g.
+func IntersectionCardinality(f, g *Filter) float64 {
+	checkSameShape(f, g)
+	return f.Cardinality() + g.Cardinality() - unionCardinality(f, g)
+}
+
+// Jaccard estimates the Jaccard similarity coefficient of the sets of
+// keys represented by f and g: the size of their intersection divided by
+// the size of their union. It returns 0 if both filters are empty.
+//
+// Jaccard panics when f and g do not have the same number of bits and
+// hash functions, the same requirement as Union and Intersect. It does
+// not modify f or g.
+func Jaccard(f, g *Filter) float64 {
+	checkSameShape(f, g)
+
+	union := unionCardinality(f, g)
+	if union == 0 {
+		return 0
+	}
+
+	inter := f.Cardinality() + g.Cardinality() - union
+	if inter < 0 {
+		inter = 0
+	}
+	return inter / union
+}
+
+// unionCardinality estimates the cardinality of the union of f and g
+// without modifying either, by applying Cardinality's estimator to the
+// per-block union of their bits.
+func unionCardinality(f, g *Filter) float64 {
+	log1p := math.Log1p
+	k := float64(f.k) - 1
+
+	var n float64
+	for i := range f.b {
+		u := f.b[i]
+		u.union(&g.b[i])
+		ones := float64(u.onescount())
+		n += log1p(-ones/BlockBits) / (k * log1p(-1./BlockBits))
+	}
+	return n
+}
@@ -0,0 +1,82 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntersectionCardinality(t *testing.T) {
+	const nbits, k = 1 << 20, 7
+	f := New(nbits, k)
+	g := New(nbits, k)
+
+	for i := uint64(0); i < 5000; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(2500); i < 7500; i++ {
+		g.Add(i * 0x9e3779b97f4a7c15)
+	}
+
+	got := IntersectionCardinality(f, g)
+	if math.IsNaN(got) {
+		t.Fatal("IntersectionCardinality = NaN")
+	}
+	const want = 2500
+	if math.Abs(got-want) > 0.05*want {
+		t.Errorf("IntersectionCardinality = %v, want approximately %v", got, want)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	const nbits, k = 1 << 20, 7
+	f := New(nbits, k)
+	g := New(nbits, k)
+
+	for i := uint64(0); i < 5000; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(2500); i < 7500; i++ {
+		g.Add(i * 0x9e3779b97f4a7c15)
+	}
+
+	// |intersection| = 2500, |union| = 7500.
+	got := Jaccard(f, g)
+	if math.IsNaN(got) {
+		t.Fatal("Jaccard = NaN")
+	}
+	const want = 2500.0 / 7500.0
+	if math.Abs(got-want) > 0.05 {
+		t.Errorf("Jaccard = %v, want approximately %v", got, want)
+	}
+}
+
+func TestJaccardEmpty(t *testing.T) {
+	f := New(BlockBits, 4)
+	g := New(BlockBits, 4)
+	if got := Jaccard(f, g); got != 0 {
+		t.Errorf("Jaccard of two empty filters = %v, want 0", got)
+	}
+}
+
+func TestCheckSameShapePanicsOnMixedLayout(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("IntersectionCardinality did not panic when mixing New and NewFast filters")
+		}
+	}()
+	f := New(1<<16, 4)
+	g := NewFast(1<<16, 4)
+	IntersectionCardinality(f, g)
+}
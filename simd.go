@@ -0,0 +1,39 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+// orBlock ORs the bits of mask into b. Add2 builds the mask for a key's k
+// probes with the portable doublehash loop, then merges it into the block
+// with a single call to orBlock, so that architectures with a vectorized
+// implementation (see block_amd64.go) only need to replace this one
+// operation, rather than the probe loop itself.
+var orBlock = orBlockGeneric
+
+// hasAllBits reports whether every bit set in mask is also set in b. Has2
+// uses it the same way Add2 uses orBlock.
+var hasAllBits = hasAllGeneric
+
+func orBlockGeneric(b, mask *block) {
+	for i := range b {
+		b[i] |= mask[i]
+	}
+}
+
+func hasAllGeneric(b, mask *block) bool {
+	for i := range b {
+		if b[i]&mask[i] != mask[i] {
+			return false
+		}
+	}
+	return true
+}
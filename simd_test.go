@@ -0,0 +1,67 @@
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blobloom
+
+import "testing"
+
+func TestOrBlockGeneric(t *testing.T) {
+	var b, mask block
+	b[0] = 0x1
+	mask[0] = 0x3
+	mask[5] = 0xf0
+
+	orBlockGeneric(&b, &mask)
+
+	if b[0] != 0x3 {
+		t.Errorf("b[0] = %#x, want %#x", b[0], 0x3)
+	}
+	if b[5] != 0xf0 {
+		t.Errorf("b[5] = %#x, want %#x", b[5], 0xf0)
+	}
+}
+
+func TestHasAllGeneric(t *testing.T) {
+	var b block
+	b[0] = 0x3
+	b[7] = 0xff
+
+	var mask block
+	mask[0] = 0x1
+	mask[7] = 0xff
+	if !hasAllGeneric(&b, &mask) {
+		t.Error("hasAllGeneric = false, want true for a subset mask")
+	}
+
+	mask[7] = 0x100
+	if hasAllGeneric(&b, &mask) {
+		t.Error("hasAllGeneric = true, want false for a bit not set in b")
+	}
+}
+
+// TestAddHasDispatch exercises Add2/Has2 through whatever orBlock/hasAllBits
+// implementation this build selects (the SIMD-accelerated ones on amd64,
+// the generic fallback elsewhere), since the two must agree.
+func TestAddHasDispatch(t *testing.T) {
+	f := New(1<<16, 7)
+
+	const n = 2000
+	for i := uint64(0); i < n; i++ {
+		f.Add(i * 0x9e3779b97f4a7c15)
+	}
+	for i := uint64(0); i < n; i++ {
+		h := i * 0x9e3779b97f4a7c15
+		if !f.Has(h) {
+			t.Fatalf("Has(%d) = false, want true (no false negatives allowed)", i)
+		}
+	}
+}